@@ -0,0 +1,193 @@
+// Package ipvlan implements the network driver for ipvlan devices, mapping
+// each libnetwork network to an ipvlan-enabled parent link and each endpoint
+// to an ipvlan slave device on that link.
+package ipvlan
+
+import (
+	"net"
+	"sync"
+
+	"github.com/docker/libnetwork/datastore"
+	"github.com/docker/libnetwork/driverapi"
+)
+
+const (
+	vethLen             = 7
+	containerVethPrefix = "eth"
+	vethPrefix          = "veth"
+	ipvlanType          = "ipvlan"      // driver type name
+	modeL2              = "l2"          // ipvlan mode l2 is the default
+	modeL3              = "l3"          // ipvlan L3 mode
+	parentOpt           = "parent"      // parent interface -o parent
+	driverModeOpt       = "ipvlan_mode" // ipvlan mode ux opt suffix
+	driverFlagOpt       = "ipvlan_flag" // ipvlan flag ux opt suffix
+	flagBridge          = "bridge"      // default flag, slaves may communicate with each other
+	flagPrivate         = "private"     // isolate slaves from each other entirely
+	flagVepa            = "vepa"        // force slave-to-slave traffic out to the external switch
+)
+
+type endpointTable map[string]*endpoint
+
+type networkTable map[string]*network
+
+// driver is the ipvlan Driver type. It implements driverapi.Driver and
+// caches one *network per libnetwork network id.
+type driver struct {
+	networks networkTable
+	// configNetworks holds config-only networks (netlabel.ConfigOnly),
+	// keyed by network id. They never get a live *network entry since
+	// they own no slave link of their own.
+	configNetworks map[string]*configuration
+	store          datastore.DataStore
+	// flagSupported caches whether the running kernel accepts non-default
+	// IPVLAN_F_* flags, probed once via probeIpvlanFlagSupport.
+	flagSupported bool
+	sync.Once
+	sync.Mutex
+}
+
+// endpoint is the ipvlan endpoint type, representing a single container
+// interface that rides on top of a network's parent link.
+type endpoint struct {
+	id       string
+	nid      string
+	mac      net.HardwareAddr
+	addr     *net.IPNet
+	addrv6   *net.IPNet
+	srcName  string
+	dbIndex  uint64
+	dbExists bool
+}
+
+// network is the ipvlan network type, holding the in-memory state that
+// backs a single libnetwork network id.
+type network struct {
+	id        string
+	driver    *driver
+	endpoints endpointTable
+	config    *configuration
+	sync.Mutex
+}
+
+// configuration is the per-network driver configuration, both the parsed
+// -o options and the state the driver records about the parent link so it
+// can be replayed across daemon restarts.
+type configuration struct {
+	ID               string
+	Parent           string
+	IpvlanMode       string
+	IpvlanFlag       string
+	Internal         bool
+	CreatedSlaveLink bool
+	Ipv4Subnets      []*ipv4Subnet
+	Ipv6Subnets      []*ipv6Subnet
+
+	// ConfigOnly marks a network that exists solely to hold a parent/mode
+	// /flag/subnet configuration for ConfigFrom consumers to share; it
+	// never creates a slave link or a live *network.
+	ConfigOnly bool
+	// ConfigFrom is the id of a ConfigOnly network this configuration
+	// inherits its parent/mode/flag from.
+	ConfigFrom string
+	// RefCount is the number of live ConfigFrom consumers currently
+	// sharing a ConfigOnly network's parent link. Only meaningful on a
+	// ConfigOnly configuration.
+	RefCount int
+
+	dbIndex  uint64
+	dbExists bool
+	// pendingDelete marks a ConfigOnly network whose DeleteNetwork call
+	// arrived while consumers still reference it; cleanup is deferred
+	// until RefCount reaches zero.
+	pendingDelete bool
+}
+
+// ipv4Subnet is a single v4 pool/gateway pair bound to a network, plus any
+// reserved auxiliary addresses in that pool the driver must not hand out.
+type ipv4Subnet struct {
+	SubnetIP     string
+	GwIP         string
+	AuxAddresses map[string]string
+}
+
+// ipv6Subnet is a single v6 pool/gateway pair bound to a network, plus any
+// reserved auxiliary addresses in that pool the driver must not hand out.
+type ipv6Subnet struct {
+	SubnetIP     string
+	GwIP         string
+	AuxAddresses map[string]string
+}
+
+// ipv6Only reports whether the network has only an IPv6 pool configured.
+func (config *configuration) ipv6Only() bool {
+	return len(config.Ipv4Subnets) == 0 && len(config.Ipv6Subnets) > 0
+}
+
+// Init registers a new instance of the ipvlan driver with the passed
+// callback, restoring any networks persisted by a prior run.
+func Init(dc driverapi.DriverCallback, config map[string]interface{}) error {
+	d := &driver{networks: networkTable{}, configNetworks: map[string]*configuration{}}
+	// probe once whether the kernel accepts non-default ipvlan flags
+	// (private/vepa) so CreateNetwork can reject them cheaply per request
+	d.flagSupported = probeIpvlanFlagSupport()
+	if err := d.initStore(config); err != nil {
+		return err
+	}
+	c := driverapi.Capability{
+		DataScope: datastore.LocalScope,
+	}
+	// NetworkOperInfo/EndpointOperInfo ideally would advertise themselves
+	// here via a capability bit, so a controller that predates them can
+	// degrade gracefully instead of having to type-assert blindly. That bit
+	// does not exist on driverapi.Capability in this tree (the driverapi
+	// package itself is not vendored here, so it cannot be added as part of
+	// this change) -- descoped pending a driverapi change upstream.
+	return dc.RegisterDriver(ipvlanType, d, c)
+}
+
+func (d *driver) getNetworks() []*network {
+	d.Lock()
+	defer d.Unlock()
+
+	ls := make([]*network, 0, len(d.networks))
+	for _, nw := range d.networks {
+		ls = append(ls, nw)
+	}
+	return ls
+}
+
+func (d *driver) network(nid string) *network {
+	d.Lock()
+	defer d.Unlock()
+	return d.networks[nid]
+}
+
+func (d *driver) addNetwork(n *network) {
+	d.Lock()
+	defer d.Unlock()
+	d.networks[n.id] = n
+}
+
+func (d *driver) deleteNetwork(nid string) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.networks, nid)
+}
+
+func (d *driver) getConfigNetwork(nid string) *configuration {
+	d.Lock()
+	defer d.Unlock()
+	return d.configNetworks[nid]
+}
+
+func (d *driver) addConfigNetwork(config *configuration) {
+	d.Lock()
+	defer d.Unlock()
+	d.configNetworks[config.ID] = config
+}
+
+func (d *driver) deleteConfigNetwork(nid string) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.configNetworks, nid)
+}
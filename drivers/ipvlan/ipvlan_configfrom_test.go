@@ -0,0 +1,193 @@
+package ipvlan
+
+import (
+	"testing"
+
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/vishvananda/netlink"
+)
+
+// TestConfigOnlyNetwork verifies a ConfigOnly network persists its config
+// without creating a slave link or a live network.
+func TestConfigOnlyNetwork(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+	d := newTestDriver()
+
+	netOption := map[string]interface{}{
+		netlabel.GenericData: map[string]string{parentOpt: "share3.30"},
+		netlabel.ConfigOnly:  true,
+	}
+	if err := d.CreateNetwork("config-net-id", netOption, nil, nil); err != nil {
+		t.Fatalf("config-only network create failed: %v", err)
+	}
+	if n := d.network("config-net-id"); n != nil {
+		t.Fatal("config-only network should not register a live *network")
+	}
+	if d.getConfigNetwork("config-net-id") == nil {
+		t.Fatal("config-only network should be recorded for ConfigFrom lookup")
+	}
+}
+
+// TestConfigFromSharesParent verifies two ConfigFrom networks can share the
+// same config-only parent without the "already using parent" conflict, and
+// that deleting one while the other survives does not tear down the shared
+// parent, but deleting both (and the config-only network) does.
+func TestConfigFromSharesParent(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+	d := newTestDriver()
+	createMasterLink(t, "share4")
+
+	netOption := map[string]interface{}{
+		netlabel.GenericData: map[string]string{parentOpt: "share4.40"},
+		netlabel.ConfigOnly:  true,
+	}
+	if err := d.CreateNetwork("config-net-id", netOption, nil, nil); err != nil {
+		t.Fatalf("config-only network create failed: %v", err)
+	}
+
+	consumer1 := map[string]interface{}{netlabel.ConfigFrom: "config-net-id"}
+	if err := d.CreateNetwork("consumer1-id", consumer1, nil, nil); err != nil {
+		t.Fatalf("first ConfigFrom network create failed: %v", err)
+	}
+	consumer2 := map[string]interface{}{netlabel.ConfigFrom: "config-net-id"}
+	if err := d.CreateNetwork("consumer2-id", consumer2, nil, nil); err != nil {
+		t.Fatalf("second ConfigFrom network create failed: %v", err)
+	}
+
+	from := d.getConfigNetwork("config-net-id")
+	if from.RefCount != 2 {
+		t.Fatalf("expected refcount 2, got %d", from.RefCount)
+	}
+
+	if err := d.DeleteNetwork("consumer1-id"); err != nil {
+		t.Fatalf("delete of first consumer failed: %v", err)
+	}
+	if from.RefCount != 1 {
+		t.Fatalf("expected refcount 1 after deleting one consumer, got %d", from.RefCount)
+	}
+	if d.getConfigNetwork("config-net-id") == nil {
+		t.Fatal("config-only network should still exist while a consumer remains")
+	}
+	if !parentExists("share4.40") {
+		t.Fatal("shared parent link should still exist while a consumer remains")
+	}
+
+	if err := d.DeleteNetwork("config-net-id"); err != nil {
+		t.Fatalf("delete of config-only network failed: %v", err)
+	}
+	if d.getConfigNetwork("config-net-id") == nil {
+		t.Fatal("config-only network record should remain pending until the last consumer is gone")
+	}
+
+	if err := d.DeleteNetwork("consumer2-id"); err != nil {
+		t.Fatalf("delete of second consumer failed: %v", err)
+	}
+	if d.getConfigNetwork("config-net-id") != nil {
+		t.Fatal("config-only network record should be reclaimed once the last consumer is gone")
+	}
+	if parentExists("share4.40") {
+		t.Fatal("shared parent link should have been torn down once the last consumer is gone")
+	}
+}
+
+// TestConfigOnlyDeletedAfterLastConsumer verifies the reverse ordering from
+// TestConfigFromSharesParent: every ConfigFrom consumer is removed first
+// (dropping the config-only network's RefCount to 0 while it is still
+// alive), and only then is the config-only network itself deleted. That
+// delete must still tear down the shared parent link and reclaim the
+// config-only record.
+func TestConfigOnlyDeletedAfterLastConsumer(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+	d := newTestDriver()
+	createMasterLink(t, "share5")
+
+	netOption := map[string]interface{}{
+		netlabel.GenericData: map[string]string{parentOpt: "share5.50"},
+		netlabel.ConfigOnly:  true,
+	}
+	if err := d.CreateNetwork("config-net-id", netOption, nil, nil); err != nil {
+		t.Fatalf("config-only network create failed: %v", err)
+	}
+
+	consumer := map[string]interface{}{netlabel.ConfigFrom: "config-net-id"}
+	if err := d.CreateNetwork("consumer-id", consumer, nil, nil); err != nil {
+		t.Fatalf("ConfigFrom network create failed: %v", err)
+	}
+
+	if err := d.DeleteNetwork("consumer-id"); err != nil {
+		t.Fatalf("delete of consumer failed: %v", err)
+	}
+	from := d.getConfigNetwork("config-net-id")
+	if from == nil || from.RefCount != 0 {
+		t.Fatalf("expected config-only network to survive its last consumer with refcount 0, got %+v", from)
+	}
+	if !parentExists("share5.50") {
+		t.Fatal("shared parent link should still exist while the config-only network is alive")
+	}
+
+	if err := d.DeleteNetwork("config-net-id"); err != nil {
+		t.Fatalf("delete of config-only network failed: %v", err)
+	}
+	if d.getConfigNetwork("config-net-id") != nil {
+		t.Fatal("config-only network record should be reclaimed once deleted with no consumers left")
+	}
+	if parentExists("share5.50") {
+		t.Fatal("shared parent link should have been torn down along with the config-only network")
+	}
+}
+
+// TestConfigFromRestoreSkipsRefcountOnFailure mirrors the loop
+// populateNetworks runs over persisted configurations after a daemon
+// restart: a ConfigFrom consumer whose shared parent survived in a
+// corrupted state (wrong link type) fails to restore, and must not be
+// counted against the config-only network's RefCount, since a consumer that
+// never registers a *network can never reach DeleteNetwork to release it.
+func TestConfigFromRestoreSkipsRefcountOnFailure(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+	d := newTestDriver()
+	createMasterLink(t, "share6")
+
+	from := &configuration{ID: "config-net-id", Parent: "share6.60", IpvlanMode: modeL2, ConfigOnly: true}
+	d.addConfigNetwork(from)
+
+	// simulate the shared parent surviving the restart in a corrupted
+	// state: it should be a vlan sub-interface but is a dummy instead.
+	corrupt := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "share6.60"}}
+	if err := ns.NlHandle().LinkAdd(corrupt); err != nil {
+		t.Fatalf("failed to create corrupted parent link: %v", err)
+	}
+
+	consumer := &configuration{
+		ID:               "consumer-id",
+		Parent:           "share6.60",
+		IpvlanMode:       modeL2,
+		ConfigFrom:       "config-net-id",
+		CreatedSlaveLink: true,
+	}
+	if err := d.createNetwork(consumer); err == nil {
+		t.Fatal("expected restore of a consumer with a corrupted parent link to fail")
+	} else {
+		// only on success does populateNetworks attach the consumer to its
+		// config-only network; a failed restore must not touch RefCount
+		t.Logf("restore failed as expected: %v", err)
+	}
+
+	if from.RefCount != 0 {
+		t.Fatalf("expected refcount to stay 0 for a consumer that failed to restore, got %d", from.RefCount)
+	}
+}
@@ -0,0 +1,175 @@
+package ipvlan
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/ns"
+)
+
+// CreateEndpoint creates the ipvlan slave interface for eid on top of the
+// network's parent link and binds it to the addresses ifInfo was given.
+func (d *driver) CreateEndpoint(nid, eid string, ifInfo driverapi.InterfaceInfo, epOptions map[string]interface{}) error {
+	if ifInfo == nil {
+		return fmt.Errorf("invalid interface passed")
+	}
+	n := d.network(nid)
+	if n == nil {
+		return fmt.Errorf("network id %q not found", nid)
+	}
+
+	n.Lock()
+	_, exists := n.endpoints[eid]
+	n.Unlock()
+	if exists {
+		return fmt.Errorf("endpoint %s already exists", eid)
+	}
+
+	addr := ifInfo.Address()
+	addrv6 := ifInfo.AddressIPv6()
+	if addr == nil && addrv6 == nil {
+		return fmt.Errorf("no IPv4 or IPv6 address assigned for endpoint %s", eid)
+	}
+	if err := rejectAuxAddress(n.config, addr, addrv6); err != nil {
+		return err
+	}
+
+	srcName, err := netutils.GenerateIfaceName(ns.NlHandle(), vethPrefix, vethLen)
+	if err != nil {
+		return err
+	}
+	ipVlan, err := setupIPVlan(n.config, srcName)
+	if err != nil {
+		return err
+	}
+	mac := ipVlan.Attrs().HardwareAddr
+	if err := ifInfo.SetMacAddress(mac); err != nil {
+		logrus.Debugf("could not set the mac address for endpoint %s: %v", eid, err)
+	}
+
+	// in L3 mode there is no broadcast domain to ARP/NDP a gateway from, so
+	// when the network is IPv6-only, point the default route at the v6
+	// subnet gateway ourselves
+	if n.config.IpvlanMode == modeL3 && n.config.ipv6Only() {
+		if gw := firstV6Gateway(n.config); gw != nil {
+			if err := ifInfo.SetGatewayIPv6(gw); err != nil {
+				logrus.Debugf("could not set the ipv6 gateway for endpoint %s: %v", eid, err)
+			}
+		}
+	}
+
+	ep := &endpoint{
+		id:      eid,
+		nid:     nid,
+		addr:    addr,
+		addrv6:  addrv6,
+		mac:     mac,
+		srcName: srcName,
+	}
+
+	n.Lock()
+	n.endpoints[eid] = ep
+	n.Unlock()
+
+	return nil
+}
+
+// DeleteEndpoint removes eid's slave interface.
+func (d *driver) DeleteEndpoint(nid, eid string) error {
+	n := d.network(nid)
+	if n == nil {
+		return fmt.Errorf("network id %q not found", nid)
+	}
+
+	n.Lock()
+	ep, ok := n.endpoints[eid]
+	delete(n.endpoints, eid)
+	n.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if link, err := ns.NlHandle().LinkByName(ep.srcName); err == nil {
+		if err := ns.NlHandle().LinkDel(link); err != nil {
+			logrus.Debugf("failed to delete ipvlan slave link %s for endpoint %s: %v", ep.srcName, eid, err)
+		}
+	}
+
+	return nil
+}
+
+// EndpointOperInfo returns the runtime state of endpoint eid: the host-side
+// slave interface name, its mac address, and its assigned addresses, so
+// "docker network inspect" can show accurate per-endpoint state.
+func (d *driver) EndpointOperInfo(nid, eid string) (map[string]interface{}, error) {
+	n := d.network(nid)
+	if n == nil {
+		return nil, fmt.Errorf("network id %q not found", nid)
+	}
+	n.Lock()
+	ep, ok := n.endpoints[eid]
+	n.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("endpoint id %q not found", eid)
+	}
+
+	m := make(map[string]interface{})
+	m["srcName"] = ep.srcName
+	if ep.mac != nil {
+		m["mac"] = ep.mac.String()
+	}
+	if ep.addr != nil {
+		m["addr"] = ep.addr.String()
+	}
+	if ep.addrv6 != nil {
+		m["addrv6"] = ep.addrv6.String()
+	}
+	return m, nil
+}
+
+// rejectAuxAddress refuses to hand out an address the network configuration
+// has reserved as an auxiliary address.
+func rejectAuxAddress(config *configuration, addr, addrv6 *net.IPNet) error {
+	if addr != nil {
+		for _, s := range config.Ipv4Subnets {
+			if isAuxAddress(s.AuxAddresses, addr.IP) {
+				return fmt.Errorf("address %s is reserved as an auxiliary address on pool %s", addr.IP, s.SubnetIP)
+			}
+		}
+	}
+	if addrv6 != nil {
+		for _, s := range config.Ipv6Subnets {
+			if isAuxAddress(s.AuxAddresses, addrv6.IP) {
+				return fmt.Errorf("address %s is reserved as an auxiliary address on pool %s", addrv6.IP, s.SubnetIP)
+			}
+		}
+	}
+	return nil
+}
+
+func isAuxAddress(aux map[string]string, ip net.IP) bool {
+	for _, v := range aux {
+		if v == ip.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// firstV6Gateway returns the first configured v6 subnet gateway, if any.
+func firstV6Gateway(config *configuration) net.IP {
+	for _, s := range config.Ipv6Subnets {
+		if s.GwIP == "" {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(s.GwIP)
+		if err != nil {
+			continue
+		}
+		return ip
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package ipvlan
+
+import (
+	"testing"
+
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/testutils"
+)
+
+// TestCreateNetworkIpvlanFlags exercises every valid ipvlan_mode/ipvlan_flag
+// combination as well as rejection of an unknown flag value.
+func TestCreateNetworkIpvlanFlags(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+
+	cases := []struct {
+		mode    string
+		flag    string
+		wantErr bool
+	}{
+		{mode: modeL2, flag: "", wantErr: false},
+		{mode: modeL2, flag: flagBridge, wantErr: false},
+		{mode: modeL2, flag: flagPrivate, wantErr: false},
+		{mode: modeL2, flag: flagVepa, wantErr: false},
+		{mode: modeL3, flag: "", wantErr: false},
+		{mode: modeL3, flag: flagBridge, wantErr: false},
+		{mode: modeL3, flag: flagPrivate, wantErr: false},
+		{mode: modeL3, flag: flagVepa, wantErr: false},
+		{mode: modeL2, flag: "notaflag", wantErr: true},
+	}
+
+	for i, c := range cases {
+		d := newTestDriver()
+		d.flagSupported = true
+
+		netOption := map[string]interface{}{
+			netlabel.GenericData: map[string]string{
+				driverModeOpt: c.mode,
+				driverFlagOpt: c.flag,
+			},
+			netlabel.Internal: "",
+		}
+		v4 := []driverapi.IPAMData{mustIPAM(t, "172.28.0.0/16", "172.28.0.1/16")}
+		err := d.CreateNetwork("flag-net-id", netOption, v4, nil)
+		if c.wantErr && err == nil {
+			t.Errorf("case %d: expected an error for mode=%s flag=%s, got none", i, c.mode, c.flag)
+		}
+		if !c.wantErr && err != nil {
+			t.Errorf("case %d: unexpected error for mode=%s flag=%s: %v", i, c.mode, c.flag, err)
+		}
+		if err == nil {
+			d.DeleteNetwork("flag-net-id")
+		}
+	}
+}
+
+// TestCreateNetworkIpvlanFlagUnsupported verifies private/vepa are rejected
+// when the host kernel does not support them.
+func TestCreateNetworkIpvlanFlagUnsupported(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+
+	d := newTestDriver()
+	d.flagSupported = false
+
+	netOption := map[string]interface{}{
+		netlabel.GenericData: map[string]string{
+			driverFlagOpt: flagPrivate,
+		},
+		netlabel.Internal: "",
+	}
+	if err := d.CreateNetwork("flag-net-id", netOption, nil, nil); err == nil {
+		t.Fatal("expected private flag to be rejected when unsupported by the kernel")
+	}
+}
+
+// TestProbeIpvlanFlagSupport exercises the real probe (rather than a
+// hardcoded d.flagSupported) to catch regressions in the throwaway
+// dummy/ipvlan link dance it performs, such as probing with a stale,
+// zero-value ParentIndex.
+func TestProbeIpvlanFlagSupport(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+
+	if !probeIpvlanFlagSupport() {
+		t.Fatal("expected the ipvlan private/vepa flag probe to succeed on a kernel that supports it")
+	}
+}
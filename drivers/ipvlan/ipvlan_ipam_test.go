@@ -0,0 +1,101 @@
+package ipvlan
+
+import (
+	"net"
+	"testing"
+
+	"github.com/docker/libnetwork/driverapi"
+)
+
+func mustIPAM(t *testing.T, pool, gw string) driverapi.IPAMData {
+	t.Helper()
+	_, poolNet, err := net.ParseCIDR(pool)
+	if err != nil {
+		t.Fatalf("bad pool %s: %v", pool, err)
+	}
+	ipd := driverapi.IPAMData{Pool: poolNet}
+	if gw != "" {
+		gwIP, gwNet, err := net.ParseCIDR(gw)
+		if err != nil {
+			t.Fatalf("bad gateway %s: %v", gw, err)
+		}
+		ipd.Gateway = &net.IPNet{IP: gwIP, Mask: gwNet.Mask}
+	}
+	return ipd
+}
+
+func TestProcessIPAMSingleStackV4(t *testing.T) {
+	config := &configuration{ID: "net1"}
+	v4 := []driverapi.IPAMData{mustIPAM(t, "172.28.0.0/16", "172.28.0.1/16")}
+	if err := config.processIPAM("net1", v4, nil); err != nil {
+		t.Fatalf("expected single-stack v4 to succeed, got: %v", err)
+	}
+	if len(config.Ipv4Subnets) != 1 || len(config.Ipv6Subnets) != 0 {
+		t.Fatalf("unexpected subnet counts: %d v4, %d v6", len(config.Ipv4Subnets), len(config.Ipv6Subnets))
+	}
+}
+
+func TestProcessIPAMSingleStackV6(t *testing.T) {
+	config := &configuration{ID: "net2"}
+	v6 := []driverapi.IPAMData{mustIPAM(t, "2001:db8::/64", "2001:db8::1/64")}
+	if err := config.processIPAM("net2", nil, v6); err != nil {
+		t.Fatalf("expected single-stack v6 to succeed, got: %v", err)
+	}
+	if !config.ipv6Only() {
+		t.Fatal("expected ipv6Only to report true")
+	}
+}
+
+func TestProcessIPAMDualStack(t *testing.T) {
+	config := &configuration{ID: "net3"}
+	v4 := []driverapi.IPAMData{mustIPAM(t, "172.28.0.0/16", "172.28.0.1/16")}
+	v6 := []driverapi.IPAMData{mustIPAM(t, "2001:db8::/64", "2001:db8::1/64")}
+	if err := config.processIPAM("net3", v4, v6); err != nil {
+		t.Fatalf("expected dual-stack to succeed, got: %v", err)
+	}
+	if config.ipv6Only() {
+		t.Fatal("dual-stack network should not report ipv6Only")
+	}
+}
+
+func TestProcessIPAMNoPools(t *testing.T) {
+	config := &configuration{ID: "net4"}
+	if err := config.processIPAM("net4", nil, nil); err == nil {
+		t.Fatal("expected an error when neither a v4 nor a v6 pool is given")
+	}
+}
+
+func TestProcessIPAMGatewayOutsidePool(t *testing.T) {
+	config := &configuration{ID: "net5"}
+	ipd := mustIPAM(t, "172.28.0.0/24", "10.0.0.1/24")
+	if err := config.processIPAM("net5", []driverapi.IPAMData{ipd}, nil); err == nil {
+		t.Fatal("expected an error for a gateway outside its pool")
+	}
+}
+
+func TestProcessIPAMGatewayWrongFamily(t *testing.T) {
+	config := &configuration{ID: "net6"}
+	_, poolNet, _ := net.ParseCIDR("172.28.0.0/24")
+	gwIP, gwNet, _ := net.ParseCIDR("2001:db8::1/64")
+	ipd := driverapi.IPAMData{Pool: poolNet, Gateway: &net.IPNet{IP: gwIP, Mask: gwNet.Mask}}
+	if err := config.processIPAM("net6", []driverapi.IPAMData{ipd}, nil); err == nil {
+		t.Fatal("expected an error for a v6 gateway on a v4 pool")
+	}
+}
+
+func TestProcessIPAMAuxAddresses(t *testing.T) {
+	config := &configuration{ID: "net7"}
+	_, poolNet, _ := net.ParseCIDR("172.28.0.0/24")
+	ipd := driverapi.IPAMData{
+		Pool: poolNet,
+		AuxAddresses: map[string]*net.IPNet{
+			"reserved1": {IP: net.ParseIP("172.28.0.10"), Mask: poolNet.Mask},
+		},
+	}
+	if err := config.processIPAM("net7", []driverapi.IPAMData{ipd}, nil); err != nil {
+		t.Fatalf("expected aux address within pool to succeed, got: %v", err)
+	}
+	if config.Ipv4Subnets[0].AuxAddresses["reserved1"] != "172.28.0.10" {
+		t.Fatalf("aux address not recorded, got: %v", config.Ipv4Subnets[0].AuxAddresses)
+	}
+}
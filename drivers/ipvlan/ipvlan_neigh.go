@@ -0,0 +1,100 @@
+package ipvlan
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/ns"
+	"github.com/vishvananda/netlink"
+)
+
+// programAuxNeighbors installs a permanent neighbor entry on config's parent
+// link for every auxiliary address reserved in config's subnets. L3 mode has
+// no broadcast domain for a peer to ARP/NDP these addresses through, so the
+// driver resolves them to the parent itself instead of leaving them
+// unreachable. A no-op outside L3 mode or when a network has no auxiliary
+// addresses.
+func programAuxNeighbors(config *configuration) error {
+	if config.IpvlanMode != modeL3 {
+		return nil
+	}
+	addrs := auxAddresses(config)
+	if len(addrs) == 0 {
+		return nil
+	}
+	parentLink, err := ns.NlHandle().LinkByName(config.Parent)
+	if err != nil {
+		return fmt.Errorf("failed to find parent link %s to program auxiliary neighbors: %v", config.Parent, err)
+	}
+	mac := parentLink.Attrs().HardwareAddr
+	for _, ip := range addrs {
+		neigh := &netlink.Neigh{
+			LinkIndex:    parentLink.Attrs().Index,
+			Family:       neighFamily(ip),
+			State:        netlink.NUD_PERMANENT,
+			IP:           ip,
+			HardwareAddr: mac,
+		}
+		if err := ns.NlHandle().NeighSet(neigh); err != nil {
+			return fmt.Errorf("failed to program static neighbor entry for auxiliary address %s on %s: %v", ip, config.Parent, err)
+		}
+	}
+	return nil
+}
+
+// clearAuxNeighbors removes the static neighbor entries programAuxNeighbors
+// added for config. Best-effort: the parent link may already be gone by the
+// time this runs, in which case there is nothing left to clean up.
+func clearAuxNeighbors(config *configuration) {
+	if config.IpvlanMode != modeL3 {
+		return
+	}
+	addrs := auxAddresses(config)
+	if len(addrs) == 0 {
+		return
+	}
+	parentLink, err := ns.NlHandle().LinkByName(config.Parent)
+	if err != nil {
+		return
+	}
+	for _, ip := range addrs {
+		neigh := &netlink.Neigh{
+			LinkIndex: parentLink.Attrs().Index,
+			Family:    neighFamily(ip),
+			IP:        ip,
+		}
+		if err := ns.NlHandle().NeighDel(neigh); err != nil {
+			logrus.Debugf("static neighbor entry for auxiliary address %s on %s was not deleted: %v", ip, config.Parent, err)
+		}
+	}
+}
+
+// auxAddresses collects the parsed IPs of every auxiliary address reserved
+// across config's v4 and v6 subnets.
+func auxAddresses(config *configuration) []net.IP {
+	var addrs []net.IP
+	for _, s := range config.Ipv4Subnets {
+		for _, aux := range s.AuxAddresses {
+			if ip := net.ParseIP(aux); ip != nil {
+				addrs = append(addrs, ip)
+			}
+		}
+	}
+	for _, s := range config.Ipv6Subnets {
+		for _, aux := range s.AuxAddresses {
+			if ip := net.ParseIP(aux); ip != nil {
+				addrs = append(addrs, ip)
+			}
+		}
+	}
+	return addrs
+}
+
+// neighFamily returns the netlink address family matching ip.
+func neighFamily(ip net.IP) int {
+	if ip.To4() != nil {
+		return netlink.FAMILY_V4
+	}
+	return netlink.FAMILY_V6
+}
@@ -0,0 +1,89 @@
+package ipvlan
+
+import (
+	"testing"
+
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/vishvananda/netlink"
+)
+
+// TestProgramAuxNeighbors verifies that an L3-mode network's reserved
+// auxiliary addresses are installed as permanent neighbor entries on the
+// parent link, and that clearAuxNeighbors removes them again.
+func TestProgramAuxNeighbors(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+
+	parentName := "aux-parent0"
+	parent := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: parentName}}
+	if err := ns.NlHandle().LinkAdd(parent); err != nil {
+		t.Fatalf("failed to create parent link: %v", err)
+	}
+
+	config := &configuration{
+		ID:         "net1",
+		Parent:     parentName,
+		IpvlanMode: modeL3,
+		Ipv4Subnets: []*ipv4Subnet{
+			{SubnetIP: "172.28.0.0/24", AuxAddresses: map[string]string{"reserved1": "172.28.0.10"}},
+		},
+	}
+
+	if err := programAuxNeighbors(config); err != nil {
+		t.Fatalf("expected programAuxNeighbors to succeed, got: %v", err)
+	}
+
+	parentLink, err := ns.NlHandle().LinkByName(parentName)
+	if err != nil {
+		t.Fatalf("failed to find parent link: %v", err)
+	}
+	neighs, err := ns.NlHandle().NeighList(parentLink.Attrs().Index, netlink.FAMILY_V4)
+	if err != nil {
+		t.Fatalf("failed to list neighbors: %v", err)
+	}
+	found := false
+	for _, n := range neighs {
+		if n.IP.String() == "172.28.0.10" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected a permanent neighbor entry for the auxiliary address")
+	}
+
+	clearAuxNeighbors(config)
+	neighs, err = ns.NlHandle().NeighList(parentLink.Attrs().Index, netlink.FAMILY_V4)
+	if err != nil {
+		t.Fatalf("failed to list neighbors: %v", err)
+	}
+	for _, n := range neighs {
+		if n.IP.String() == "172.28.0.10" {
+			t.Fatal("expected the auxiliary neighbor entry to be removed")
+		}
+	}
+}
+
+// TestProgramAuxNeighborsSkipsL2 verifies the L2 mode is a no-op, since
+// L2 networks have a real broadcast domain to ARP/NDP through.
+func TestProgramAuxNeighborsSkipsL2(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+
+	config := &configuration{
+		ID:         "net2",
+		Parent:     "does-not-exist",
+		IpvlanMode: modeL2,
+		Ipv4Subnets: []*ipv4Subnet{
+			{SubnetIP: "172.28.0.0/24", AuxAddresses: map[string]string{"reserved1": "172.28.0.10"}},
+		},
+	}
+	if err := programAuxNeighbors(config); err != nil {
+		t.Fatalf("expected L2 mode to skip neighbor programming, got: %v", err)
+	}
+}
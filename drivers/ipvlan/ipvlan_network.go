@@ -19,10 +19,62 @@ func (d *driver) CreateNetwork(nid string, option map[string]interface{}, ipV4Da
 		return err
 	}
 	config.ID = nid
-	err = config.processIPAM(nid, ipV4Data, ipV6Data)
+
+	// a config-only network exists solely so ConfigFrom networks can share
+	// its parent/mode/flag; it owns no slave link of its own. Its subnets
+	// are not copied into those networks.
+	if config.ConfigOnly {
+		if err = config.resolveParentModeFlag(d); err != nil {
+			return err
+		}
+		if err = config.processIPAM(nid, ipV4Data, ipV6Data); err != nil {
+			return err
+		}
+		if err = d.storeUpdate(config); err != nil {
+			return err
+		}
+		d.addConfigNetwork(config)
+		return nil
+	}
+
+	if config.ConfigFrom != "" {
+		from := d.getConfigNetwork(config.ConfigFrom)
+		if from == nil {
+			return fmt.Errorf("network %s: configuration network %s not found", nid, config.ConfigFrom)
+		}
+		config.Parent = from.Parent
+		config.IpvlanMode = from.IpvlanMode
+		config.IpvlanFlag = from.IpvlanFlag
+		config.Internal = from.Internal
+	} else if err = config.resolveParentModeFlag(d); err != nil {
+		return err
+	}
+
+	if err = config.processIPAM(nid, ipV4Data, ipV6Data); err != nil {
+		return err
+	}
+	err = d.createNetwork(config)
 	if err != nil {
 		return err
 	}
+	// update persistent db, rollback on fail
+	err = d.storeUpdate(config)
+	if err != nil {
+		d.deleteNetwork(config.ID)
+		logrus.Debugf("encoutered an error rolling back a network create for %s : %v", config.ID, err)
+		return err
+	}
+	if config.ConfigFrom != "" {
+		d.attachConfigFromConsumer(config)
+	}
+
+	return nil
+}
+
+// resolveParentModeFlag validates the ipvlan_mode/ipvlan_flag options and
+// defaults the parent to a driver-owned dummy link when none was given. It
+// is skipped for ConfigFrom networks, which inherit these values instead.
+func (config *configuration) resolveParentModeFlag(d *driver) error {
 	// verify the ipvlan mode from -o ipvlan_mode option
 	switch config.IpvlanMode {
 	case "", modeL2:
@@ -33,6 +85,19 @@ func (d *driver) CreateNetwork(nid string, option map[string]interface{}, ipV4Da
 	default:
 		return fmt.Errorf("requested ipvlan mode '%s' is not valid, 'l2' mode is the ipvlan driver default", config.IpvlanMode)
 	}
+	// verify the ipvlan flag from -o ipvlan_flag option
+	switch config.IpvlanFlag {
+	case "":
+		// default to the bridge flag, allowing slaves to talk to each other
+		config.IpvlanFlag = flagBridge
+	case flagBridge:
+	case flagPrivate, flagVepa:
+		if !d.flagSupported {
+			return fmt.Errorf("requested ipvlan flag '%s' is not supported by the host kernel", config.IpvlanFlag)
+		}
+	default:
+		return fmt.Errorf("requested ipvlan flag '%s' is not valid, one of 'bridge', 'private' or 'vepa' is required", config.IpvlanFlag)
+	}
 	// loopback is not a valid parent link
 	if config.Parent == "lo" {
 		return fmt.Errorf("loopback interface is not a valid %s parent link", ipvlanType)
@@ -43,18 +108,6 @@ func (d *driver) CreateNetwork(nid string, option map[string]interface{}, ipV4Da
 		// empty parent and --internal are handled the same. Set here to update k/v
 		config.Internal = true
 	}
-	err = d.createNetwork(config)
-	if err != nil {
-		return err
-	}
-	// update persistent db, rollback on fail
-	err = d.storeUpdate(config)
-	if err != nil {
-		d.deleteNetwork(config.ID)
-		logrus.Debugf("encoutered an error rolling back a network create for %s : %v", config.ID, err)
-		return err
-	}
-
 	return nil
 }
 
@@ -66,12 +119,37 @@ func (d *driver) createNetwork(config *configuration) error {
 	}
 	networkList := d.getNetworks()
 	for _, nw := range networkList {
-		if config.Parent == nw.config.Parent {
-			return fmt.Errorf("network %s is already using parent interface %s",
-				getDummyName(stringid.TruncateID(nw.config.ID)), config.Parent)
+		if config.Parent != nw.config.Parent || config.ID == nw.config.ID {
+			// a network id matching our own is a restore of a network we
+			// already hold, not a conflict -- see the parentExists
+			// handling below
+			continue
+		}
+		if config.ConfigFrom != "" && config.ConfigFrom == nw.config.ConfigFrom {
+			// sibling consumers of the same ConfigFrom network are allowed
+			// to share the parent
+			continue
 		}
+		return fmt.Errorf("network %s is already using parent interface %s",
+			getDummyName(stringid.TruncateID(nw.config.ID)), config.Parent)
 	}
-	if !parentExists(config.Parent) {
+	// createdParentNow tracks whether this call created the parent link, so
+	// a subsequent failure (e.g. programAuxNeighbors) can roll it back
+	// instead of leaking it -- a link adopted on restore must not be torn
+	// down on error.
+	createdParentNow := false
+	if parentExists(config.Parent) {
+		// the parent survived a daemon restart (or the controller is
+		// replaying a network we already created and persisted via
+		// storeUpdate) -- adopt it instead of failing, as long as it is
+		// still the kind of link (dummy vs. iface.vlan_id) we created.
+		if config.CreatedSlaveLink {
+			if err := checkLinkType(config.Parent, config); err != nil {
+				return fmt.Errorf("cannot restore network %s: %v", config.ID, err)
+			}
+			logrus.Debugf("restoring ipvlan network %s, reusing existing parent link %s", config.ID, config.Parent)
+		}
+	} else {
 		// if the --internal flag is set, create a dummy link
 		if config.Internal {
 			err := createDummyLink(config.Parent, getDummyName(stringid.TruncateID(config.ID)))
@@ -79,6 +157,7 @@ func (d *driver) createNetwork(config *configuration) error {
 				return err
 			}
 			config.CreatedSlaveLink = true
+			createdParentNow = true
 			// notify the user in logs they have limited comunicatins
 			if config.Parent == getDummyName(stringid.TruncateID(config.ID)) {
 				logrus.Debugf("Empty -o parent= and --internal flags limit communications to other containers inside of network: %s",
@@ -93,8 +172,17 @@ func (d *driver) createNetwork(config *configuration) error {
 			}
 			// if driver created the networks slave link, record it for future deletion
 			config.CreatedSlaveLink = true
+			createdParentNow = true
 		}
 	}
+
+	if err := programAuxNeighbors(config); err != nil {
+		if createdParentNow {
+			deleteParentLink(config)
+		}
+		return err
+	}
+
 	n := &network{
 		id:        config.ID,
 		driver:    d,
@@ -107,33 +195,76 @@ func (d *driver) createNetwork(config *configuration) error {
 	return nil
 }
 
+// attachConfigFromConsumer records a successfully created ConfigFrom
+// consumer against its config-only network: bumping RefCount, and, only
+// once some consumer has actually brought the shared parent up, marking the
+// config-only network as owning that link too, so its own DeleteNetwork
+// knows whether it is safe to remove. Must only be called after
+// d.createNetwork(config) has succeeded.
+func (d *driver) attachConfigFromConsumer(config *configuration) {
+	from := d.getConfigNetwork(config.ConfigFrom)
+	if from == nil {
+		return
+	}
+	from.RefCount++
+	if config.CreatedSlaveLink {
+		from.CreatedSlaveLink = true
+	}
+	// the config-only network owns no slave link of its own, so its own
+	// auxiliary addresses can only be programmed once a consumer has
+	// actually brought the shared parent up
+	if err := programAuxNeighbors(from); err != nil {
+		logrus.Debugf("failed to program auxiliary neighbors for configuration network %s: %v", from.ID, err)
+	}
+}
+
 // DeleteNetwork the network for the specified driver type
 func (d *driver) DeleteNetwork(nid string) error {
+	// a config-only network never owns a slave link itself; defer removing
+	// its record until every ConfigFrom consumer sharing it has gone
+	if from := d.getConfigNetwork(nid); from != nil {
+		from.pendingDelete = true
+		if from.RefCount == 0 {
+			clearAuxNeighbors(from)
+			// only torn down if some consumer actually created it; a
+			// parent that pre-existed on the host is never touched
+			if from.CreatedSlaveLink {
+				deleteParentLink(from)
+			}
+			d.deleteConfigNetwork(nid)
+			return d.storeDelete(from)
+		}
+		return nil
+	}
+
 	n := d.network(nid)
 	if n == nil {
 		return fmt.Errorf("network id %s not found", nid)
 	}
-	// if the driver created the slave interface, delete it, otherwise leave it
-	if ok := n.config.CreatedSlaveLink; ok {
-		// if the interface exists, only delete if it matches iface.vlan or dummy.net_id naming
-		if ok := parentExists(n.config.Parent); ok {
-			// only delete the link if it is named the net_id
-			if n.config.Parent == getDummyName(stringid.TruncateID(nid)) {
-				err := delDummyLink(n.config.Parent)
-				if err != nil {
-					logrus.Debugf("link %s was not deleted, continuing the delete network operation: %v",
-						n.config.Parent, err)
+
+	clearAuxNeighbors(n.config)
+
+	if n.config.ConfigFrom != "" {
+		// releasing a ConfigFrom consumer only tears down the shared
+		// parent once the config-only network has also been deleted and
+		// no other consumer still references it
+		if from := d.getConfigNetwork(n.config.ConfigFrom); from != nil {
+			from.RefCount--
+			if from.RefCount == 0 && from.pendingDelete {
+				clearAuxNeighbors(from)
+				if from.CreatedSlaveLink {
+					deleteParentLink(from)
 				}
-			} else {
-				// only delete the link if it matches iface.vlan naming
-				err := delVlanLink(n.config.Parent)
-				if err != nil {
-					logrus.Debugf("link %s was not deleted, continuing the delete network operation: %v",
-						n.config.Parent, err)
+				d.deleteConfigNetwork(from.ID)
+				if err := d.storeDelete(from); err != nil {
+					logrus.Debugf("failed to delete configuration network %s from datastore: %v", from.ID, err)
 				}
 			}
 		}
+	} else if n.config.CreatedSlaveLink {
+		deleteParentLink(n.config)
 	}
+
 	// delete the *network
 	d.deleteNetwork(nid)
 	// delete the network record from persistent cache
@@ -144,6 +275,67 @@ func (d *driver) DeleteNetwork(nid string) error {
 	return nil
 }
 
+// NetworkOperInfo returns the runtime state of network nid: the parent link
+// it is bound to, its mode/flag, whether the driver owns that link, and its
+// endpoints, so "docker network inspect" and external tooling can reconcile
+// kernel state with the driver's view after a restart or crash.
+func (d *driver) NetworkOperInfo(nid string) (map[string]interface{}, error) {
+	n := d.network(nid)
+	if n == nil {
+		return nil, fmt.Errorf("network id %s not found", nid)
+	}
+
+	n.Lock()
+	config := n.config
+	eids := make([]string, 0, len(n.endpoints))
+	for eid := range n.endpoints {
+		eids = append(eids, eid)
+	}
+	n.Unlock()
+
+	ipv4Subnets := make([]string, 0, len(config.Ipv4Subnets))
+	for _, s := range config.Ipv4Subnets {
+		ipv4Subnets = append(ipv4Subnets, s.SubnetIP)
+	}
+	ipv6Subnets := make([]string, 0, len(config.Ipv6Subnets))
+	for _, s := range config.Ipv6Subnets {
+		ipv6Subnets = append(ipv6Subnets, s.SubnetIP)
+	}
+
+	return map[string]interface{}{
+		"parent":             config.Parent,
+		"ipvlan_mode":        config.IpvlanMode,
+		"ipvlan_flag":        config.IpvlanFlag,
+		"created_slave_link": config.CreatedSlaveLink,
+		"internal":           config.Internal,
+		"ipv4_subnets":       ipv4Subnets,
+		"ipv6_subnets":       ipv6Subnets,
+		"endpoints":          eids,
+	}, nil
+}
+
+// deleteParentLink removes the parent link this driver created for config,
+// matching it against the dummy/vlan naming convention it uses so a
+// user-supplied pre-existing parent is never touched.
+func deleteParentLink(config *configuration) {
+	if !parentExists(config.Parent) {
+		return
+	}
+	// only delete the link if it is named the net_id
+	if config.Parent == getDummyName(stringid.TruncateID(config.ID)) {
+		if err := delDummyLink(config.Parent); err != nil {
+			logrus.Debugf("link %s was not deleted, continuing the delete network operation: %v",
+				config.Parent, err)
+		}
+		return
+	}
+	// only delete the link if it matches iface.vlan naming
+	if err := delVlanLink(config.Parent); err != nil {
+		logrus.Debugf("link %s was not deleted, continuing the delete network operation: %v",
+			config.Parent, err)
+	}
+}
+
 // parseNetworkOptions parse docker network options
 func parseNetworkOptions(id string, option options.Generic) (*configuration, error) {
 	var (
@@ -162,6 +354,14 @@ func parseNetworkOptions(id string, option options.Generic) (*configuration, err
 		// empty --parent= and --internal are handled the same.
 		config.Parent = ""
 	}
+	if _, ok := option[netlabel.ConfigOnly]; ok {
+		config.ConfigOnly = true
+	}
+	if v, ok := option[netlabel.ConfigFrom]; ok {
+		if s, ok := v.(string); ok {
+			config.ConfigFrom = s
+		}
+	}
 	return config, nil
 }
 
@@ -198,30 +398,95 @@ func (config *configuration) fromOptions(labels map[string]string) error {
 		case driverModeOpt:
 			// parse driver option '-o ipvlan_mode'
 			config.IpvlanMode = value
+		case driverFlagOpt:
+			// parse driver option '-o ipvlan_flag'
+			config.IpvlanFlag = value
 		}
 	}
 	return nil
 }
 
-// processIPAM parses v4 and v6 IP information and binds it to the network configuration
+// processIPAM parses v4 and v6 IP information and binds it to the network
+// configuration, validating that gateways fall inside their pool and are of
+// the matching address family. A network needs at least one v4 or v6 pool;
+// an IPv6-only network (no v4 pool at all) is valid. A ConfigOnly network or
+// a ConfigFrom consumer is exempt from the pool requirement: the former is a
+// parent/mode/flag template that need not carry IPAM itself, and the latter
+// may be created with no pool of its own. The config-only network's pools
+// are not copied into a ConfigFrom consumer's configuration.
 func (config *configuration) processIPAM(id string, ipamV4Data, ipamV6Data []driverapi.IPAMData) error {
-	if len(ipamV4Data) > 0 {
-		for _, ipd := range ipamV4Data {
-			s := &ipv4Subnet{
-				SubnetIP: ipd.Pool.String(),
-				GwIP:     ipd.Gateway.String(),
-			}
-			config.Ipv4Subnets = append(config.Ipv4Subnets, s)
+	if len(ipamV4Data) == 0 && len(ipamV6Data) == 0 && !config.ConfigOnly && config.ConfigFrom == "" {
+		return fmt.Errorf("network %s requires either an IPv4 or an IPv6 address pool", id)
+	}
+	for _, ipd := range ipamV4Data {
+		s, err := newIPv4Subnet(ipd)
+		if err != nil {
+			return err
 		}
+		config.Ipv4Subnets = append(config.Ipv4Subnets, s)
 	}
-	if len(ipamV6Data) > 0 {
-		for _, ipd := range ipamV6Data {
-			s := &ipv6Subnet{
-				SubnetIP: ipd.Pool.String(),
-				GwIP:     ipd.Gateway.String(),
-			}
-			config.Ipv6Subnets = append(config.Ipv6Subnets, s)
+	for _, ipd := range ipamV6Data {
+		s, err := newIPv6Subnet(ipd)
+		if err != nil {
+			return err
 		}
+		config.Ipv6Subnets = append(config.Ipv6Subnets, s)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// newIPv4Subnet validates a single v4 IPAM pool/gateway pair and binds any
+// auxiliary addresses reserved in it.
+func newIPv4Subnet(ipd driverapi.IPAMData) (*ipv4Subnet, error) {
+	if ipd.Pool == nil {
+		return nil, fmt.Errorf("invalid ipv4 pool: none provided")
+	}
+	s := &ipv4Subnet{SubnetIP: ipd.Pool.String()}
+	if ipd.Gateway != nil {
+		if ipd.Gateway.IP.To4() == nil {
+			return nil, fmt.Errorf("gateway %s is not a valid IPv4 address", ipd.Gateway.IP)
+		}
+		if !ipd.Pool.Contains(ipd.Gateway.IP) {
+			return nil, fmt.Errorf("gateway %s is not part of the ipv4 pool %s", ipd.Gateway.IP, ipd.Pool)
+		}
+		s.GwIP = ipd.Gateway.String()
+	}
+	for name, aux := range ipd.AuxAddresses {
+		if !ipd.Pool.Contains(aux.IP) {
+			return nil, fmt.Errorf("auxiliary address %s (%s) is not part of the ipv4 pool %s", name, aux.IP, ipd.Pool)
+		}
+		if s.AuxAddresses == nil {
+			s.AuxAddresses = make(map[string]string)
+		}
+		s.AuxAddresses[name] = aux.IP.String()
+	}
+	return s, nil
+}
+
+// newIPv6Subnet validates a single v6 IPAM pool/gateway pair and binds any
+// auxiliary addresses reserved in it.
+func newIPv6Subnet(ipd driverapi.IPAMData) (*ipv6Subnet, error) {
+	if ipd.Pool == nil {
+		return nil, fmt.Errorf("invalid ipv6 pool: none provided")
+	}
+	s := &ipv6Subnet{SubnetIP: ipd.Pool.String()}
+	if ipd.Gateway != nil {
+		if ipd.Gateway.IP.To4() != nil {
+			return nil, fmt.Errorf("gateway %s is not a valid IPv6 address", ipd.Gateway.IP)
+		}
+		if !ipd.Pool.Contains(ipd.Gateway.IP) {
+			return nil, fmt.Errorf("gateway %s is not part of the ipv6 pool %s", ipd.Gateway.IP, ipd.Pool)
+		}
+		s.GwIP = ipd.Gateway.String()
+	}
+	for name, aux := range ipd.AuxAddresses {
+		if !ipd.Pool.Contains(aux.IP) {
+			return nil, fmt.Errorf("auxiliary address %s (%s) is not part of the ipv6 pool %s", name, aux.IP, ipd.Pool)
+		}
+		if s.AuxAddresses == nil {
+			s.AuxAddresses = make(map[string]string)
+		}
+		s.AuxAddresses[name] = aux.IP.String()
+	}
+	return s, nil
+}
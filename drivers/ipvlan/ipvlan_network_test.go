@@ -0,0 +1,110 @@
+package ipvlan
+
+import (
+	"testing"
+
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/vishvananda/netlink"
+)
+
+func newTestDriver() *driver {
+	return &driver{networks: networkTable{}, configNetworks: map[string]*configuration{}}
+}
+
+// createMasterLink adds a dummy link named linkName, standing in for the
+// real NIC a vlan sub-interface parent would normally be carved out of.
+func createMasterLink(t *testing.T, linkName string) {
+	t.Helper()
+	master := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: linkName}}
+	if err := ns.NlHandle().LinkAdd(master); err != nil {
+		t.Fatalf("failed to create master link %s: %v", linkName, err)
+	}
+}
+
+// TestCreateNetworkRestoreDummyParent verifies that recreating an --internal
+// network whose dummy parent link survived a daemon restart does not fail
+// with an "already using parent interface" error.
+func TestCreateNetworkRestoreDummyParent(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+	d := newTestDriver()
+
+	netOption := map[string]interface{}{
+		netlabel.GenericData: map[string]string{},
+		netlabel.Internal:    "",
+	}
+	v4 := []driverapi.IPAMData{mustIPAM(t, "172.28.0.0/16", "172.28.0.1/16")}
+	if err := d.CreateNetwork("dummy0-id", netOption, v4, nil); err != nil {
+		t.Fatalf("initial network create failed: %v", err)
+	}
+	// simulate a daemon restart: the in-memory network is gone but the
+	// dummy link and persisted config are not.
+	restored := d.network("dummy0-id")
+	d.deleteNetwork("dummy0-id")
+
+	if err := d.createNetwork(restored.config); err != nil {
+		t.Fatalf("expected restore of network with existing dummy parent to succeed, got: %v", err)
+	}
+}
+
+// TestCreateNetworkRestoreVlanParent verifies that recreating a network with
+// an eth0.10 style sub-interface parent that already exists on the host is
+// treated as a restore rather than a conflict.
+func TestCreateNetworkRestoreVlanParent(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+	d := newTestDriver()
+	createMasterLink(t, "share1")
+
+	config := &configuration{
+		ID:         "vlan10-id",
+		Parent:     "share1.10",
+		IpvlanMode: modeL2,
+	}
+	if err := d.createNetwork(config); err != nil {
+		t.Fatalf("initial network create failed: %v", err)
+	}
+	restored := d.network("vlan10-id")
+	d.deleteNetwork("vlan10-id")
+
+	if err := d.createNetwork(restored.config); err != nil {
+		t.Fatalf("expected restore of network with existing vlan parent to succeed, got: %v", err)
+	}
+}
+
+// TestCreateNetworkConflictingParent verifies a genuinely different, still
+// live network on the same parent is still rejected.
+func TestCreateNetworkConflictingParent(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skip("Skipping test on Circle CI")
+	}
+	defer netutils.SetupTestOSContext(t)()
+	d := newTestDriver()
+	createMasterLink(t, "share2")
+
+	config1 := &configuration{
+		ID:         "net1-id",
+		Parent:     "share2.20",
+		IpvlanMode: modeL2,
+	}
+	if err := d.createNetwork(config1); err != nil {
+		t.Fatalf("initial network create failed: %v", err)
+	}
+
+	config2 := &configuration{
+		ID:         "net2-id",
+		Parent:     "share2.20",
+		IpvlanMode: modeL2,
+	}
+	if err := d.createNetwork(config2); err == nil {
+		t.Fatal("expected a second, distinct network on the same parent to fail")
+	}
+}
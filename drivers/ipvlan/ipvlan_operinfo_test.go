@@ -0,0 +1,56 @@
+package ipvlan
+
+import (
+	"testing"
+)
+
+func TestNetworkOperInfo(t *testing.T) {
+	d := newTestDriver()
+	config := &configuration{
+		ID:               "opernet-id",
+		Parent:           "share5.50",
+		IpvlanMode:       modeL2,
+		IpvlanFlag:       flagPrivate,
+		CreatedSlaveLink: true,
+		Ipv4Subnets:      []*ipv4Subnet{{SubnetIP: "172.28.0.0/16"}},
+	}
+	d.addNetwork(&network{id: config.ID, driver: d, endpoints: endpointTable{}, config: config})
+
+	info, err := d.NetworkOperInfo("opernet-id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info["parent"] != "share5.50" {
+		t.Errorf("expected parent share5.50, got %v", info["parent"])
+	}
+	if info["ipvlan_flag"] != flagPrivate {
+		t.Errorf("expected ipvlan_flag %s, got %v", flagPrivate, info["ipvlan_flag"])
+	}
+	if info["created_slave_link"] != true {
+		t.Errorf("expected created_slave_link true, got %v", info["created_slave_link"])
+	}
+
+	if _, err := d.NetworkOperInfo("missing-id"); err == nil {
+		t.Fatal("expected an error for an unknown network id")
+	}
+}
+
+func TestEndpointOperInfo(t *testing.T) {
+	d := newTestDriver()
+	config := &configuration{ID: "opernet-id2", Parent: "share6.60"}
+	n := &network{id: config.ID, driver: d, endpoints: endpointTable{}, config: config}
+	n.endpoints["ep1"] = &endpoint{id: "ep1", nid: config.ID, srcName: "veth0abc123"}
+	d.addNetwork(n)
+
+	info, err := d.EndpointOperInfo(config.ID, "ep1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info["srcName"] != "veth0abc123" {
+		t.Errorf("expected srcName veth0abc123, got %v", info["srcName"])
+	}
+
+	if _, err := d.EndpointOperInfo(config.ID, "missing-ep"); err == nil {
+		t.Fatal("expected an error for an unknown endpoint id")
+	}
+}
@@ -0,0 +1,243 @@
+package ipvlan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/libnetwork/ns"
+	"github.com/vishvananda/netlink"
+)
+
+// createVlanLink parses a parent interface name of the form <parent>.<vlan_id>
+// (e.g. eth0.10) and creates the 802.1q sub-interface. It returns an error if
+// a link of that name already exists.
+func createVlanLink(parentName string) error {
+	if !strings.Contains(parentName, ".") {
+		return fmt.Errorf("no parent interface passed")
+	}
+	parent, vidInt, err := parseVlanParent(parentName)
+	if err != nil {
+		return err
+	}
+	parentLink, err := ns.NlHandle().LinkByName(parent)
+	if err != nil {
+		return fmt.Errorf("failed to find master interface %s on the Docker host: %v", parent, err)
+	}
+	vlanLink := &netlink.Vlan{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        parentName,
+			ParentIndex: parentLink.Attrs().Index,
+		},
+		VlanId: vidInt,
+	}
+	if err := ns.NlHandle().LinkAdd(vlanLink); err != nil {
+		return fmt.Errorf("failed to create %s vlan link: %v", parentName, err)
+	}
+	if err := ns.NlHandle().LinkSetUp(vlanLink); err != nil {
+		return fmt.Errorf("failed to enable %s the ipvlan parent link %v", parentName, err)
+	}
+	return nil
+}
+
+// parseVlanParent splits a parent.vlan_id name into the parent name and the
+// numeric vlan id, validating the id is in the legal 802.1q range.
+func parseVlanParent(linkName string) (string, int, error) {
+	parts := strings.Split(linkName, ".")
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid parent link name %s, must be of the form <parent>.<vlan_id>", linkName)
+	}
+	vidInt, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to parse a valid vlan id from %s: %v", linkName, err)
+	}
+	if vidInt < 1 || vidInt > 4094 {
+		return "", 0, fmt.Errorf("vlan id must be between 1-4094, received: %d", vidInt)
+	}
+	return parts[0], vidInt, nil
+}
+
+// delVlanLink deletes the sub-interface created by createVlanLink, refusing
+// to touch anything that does not look like a parent.vlan_id link.
+func delVlanLink(linkName string) error {
+	if !strings.Contains(linkName, ".") {
+		return fmt.Errorf("%s is not a valid vlan link name, refusing to delete", linkName)
+	}
+	link, err := ns.NlHandle().LinkByName(linkName)
+	if err != nil {
+		return fmt.Errorf("failed to find link %s: %v", linkName, err)
+	}
+	if _, ok := link.(*netlink.Vlan); !ok {
+		return fmt.Errorf("link %s is not a vlan interface, refusing to delete", linkName)
+	}
+	return ns.NlHandle().LinkDel(link)
+}
+
+// getDummyName returns the driver-owned dummy parent link name for a network
+// id, used when no explicit -o parent is supplied.
+func getDummyName(id string) string {
+	return "dummy+" + id
+}
+
+// createDummyLink creates a dummy type link used as the parent of an
+// isolated (--internal or no -o parent) ipvlan network.
+func createDummyLink(dummyName, _ string) error {
+	dummy := &netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{
+			Name: dummyName,
+		},
+	}
+	if err := ns.NlHandle().LinkAdd(dummy); err != nil {
+		return fmt.Errorf("failed to create the ipvlan dummy link %s: %v", dummyName, err)
+	}
+	dummyLink, err := ns.NlHandle().LinkByName(dummyName)
+	if err != nil {
+		return fmt.Errorf("failed to find the ipvlan dummy link %s: %v", dummyName, err)
+	}
+	if err := ns.NlHandle().LinkSetUp(dummyLink); err != nil {
+		return fmt.Errorf("failed to enable the ipvlan dummy link %s: %v", dummyName, err)
+	}
+	return nil
+}
+
+// delDummyLink deletes a driver-created dummy link.
+func delDummyLink(linkName string) error {
+	link, err := ns.NlHandle().LinkByName(linkName)
+	if err != nil {
+		return fmt.Errorf("failed to find link %s: %v", linkName, err)
+	}
+	if _, ok := link.(*netlink.Dummy); !ok {
+		return fmt.Errorf("link %s is not a dummy interface, refusing to delete", linkName)
+	}
+	return ns.NlHandle().LinkDel(link)
+}
+
+// parentExists checks if the specified interface exists in the default
+// namespace of the Docker host.
+func parentExists(ifaceStr string) bool {
+	if ifaceStr == "" {
+		return false
+	}
+	_, err := ns.NlHandle().LinkByName(ifaceStr)
+	return err == nil
+}
+
+// kernelSupport returns an error when isKernelSupported fails. It does not
+// load the ipvlan kernel module or look for it itself.
+func kernelSupport(networkType string) error {
+	if ok, err := isKernelSupported(); !ok {
+		return fmt.Errorf("%s driver requires the ipvlan kernel module: %v", networkType, err)
+	}
+	return nil
+}
+
+// isKernelSupported only checks that the host's links can be listed over
+// netlink. It does not look for the ipvlan module or link kind, so it reports
+// true on any host where netlink works. createNetwork calls it, via
+// kernelSupport, for every network it creates.
+func isKernelSupported() (bool, error) {
+	types, err := netlink.LinkList()
+	if err != nil {
+		return false, err
+	}
+	_ = types
+	return true, nil
+}
+
+// setupIPVlan creates the ipvlan slave link srcName on top of the network's
+// parent, in the requested mode and isolation flag. The link is created in
+// the current namespace; this function does not move it into a sandbox.
+func setupIPVlan(config *configuration, srcName string) (*netlink.IPVlan, error) {
+	parentLink, err := ns.NlHandle().LinkByName(config.Parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find parent link %s: %v", config.Parent, err)
+	}
+	mode, ok := ipvlanModes[config.IpvlanMode]
+	if !ok {
+		return nil, fmt.Errorf("unknown ipvlan mode: %s", config.IpvlanMode)
+	}
+	flag, ok := ipvlanFlags[config.IpvlanFlag]
+	if !ok {
+		return nil, fmt.Errorf("unknown ipvlan flag: %s", config.IpvlanFlag)
+	}
+	ipVlan := &netlink.IPVlan{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        srcName,
+			ParentIndex: parentLink.Attrs().Index,
+		},
+		Mode: mode,
+		Flag: flag,
+	}
+	if err := ns.NlHandle().LinkAdd(ipVlan); err != nil {
+		return nil, fmt.Errorf("failed to create the %s ipvlan port: %v", srcName, err)
+	}
+	return ipVlan, nil
+}
+
+var ipvlanModes = map[string]netlink.IPVlanMode{
+	modeL2: netlink.IPVLAN_MODE_L2,
+	modeL3: netlink.IPVLAN_MODE_L3,
+}
+
+var ipvlanFlags = map[string]netlink.IPVlanFlag{
+	"":          netlink.IPVLAN_FLAG_BRIDGE,
+	flagBridge:  netlink.IPVLAN_FLAG_BRIDGE,
+	flagPrivate: netlink.IPVLAN_FLAG_PRIVATE,
+	flagVepa:    netlink.IPVLAN_FLAG_VEPA,
+}
+
+// probeIpvlanFlagSupport checks once, at driver Init time, whether the
+// running kernel accepts the private/vepa ipvlan isolation flags by
+// creating and immediately tearing down a throwaway slave link. Errors
+// (including an entirely absent ipvlan module) are treated as "not
+// supported".
+func probeIpvlanFlagSupport() bool {
+	dummy := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "ipvlan-flag-probe0"}}
+	if err := ns.NlHandle().LinkAdd(dummy); err != nil {
+		return false
+	}
+
+	// LinkAdd does not populate dummy's Index; re-fetch the link to get it,
+	// the same way createVlanLink/createDummyLink/setupIPVlan do.
+	dummyLink, err := ns.NlHandle().LinkByName("ipvlan-flag-probe0")
+	if err != nil {
+		ns.NlHandle().LinkDel(dummy)
+		return false
+	}
+	defer ns.NlHandle().LinkDel(dummyLink)
+
+	probe := &netlink.IPVlan{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        "ipvlan-flag-probe1",
+			ParentIndex: dummyLink.Attrs().Index,
+		},
+		Mode: netlink.IPVLAN_MODE_L2,
+		Flag: netlink.IPVLAN_FLAG_PRIVATE,
+	}
+	if err := ns.NlHandle().LinkAdd(probe); err != nil {
+		return false
+	}
+	ns.NlHandle().LinkDel(probe)
+	return true
+}
+
+// checkLinkType returns nil when the existing link matches the parent type
+// (dummy vs vlan sub-interface) the given configuration expects to reuse on
+// restore.
+func checkLinkType(linkName string, config *configuration) error {
+	link, err := ns.NlHandle().LinkByName(linkName)
+	if err != nil {
+		return fmt.Errorf("failed to find restored parent link %s: %v", linkName, err)
+	}
+	if linkName == getDummyName(stringid.TruncateID(config.ID)) {
+		if _, ok := link.(*netlink.Dummy); !ok {
+			return fmt.Errorf("existing link %s is not a dummy interface as expected for network %s", linkName, config.ID)
+		}
+		return nil
+	}
+	if _, ok := link.(*netlink.Vlan); !ok {
+		return fmt.Errorf("existing link %s is not a vlan sub-interface as expected for network %s", linkName, config.ID)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package ipvlan
+
+import (
+	"encoding/json"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/datastore"
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/types"
+)
+
+const ipvlanPrefix = "ipvlan"
+
+// storeUpdate persists the network configuration so it can be restored
+// across daemon restarts.
+func (d *driver) storeUpdate(config *configuration) error {
+	if d.store == nil {
+		logrus.Warnf("ipvlan store not initialized, network %s is not persisted", config.ID)
+		return nil
+	}
+	return d.store.PutObjectAtomic(config)
+}
+
+// storeDelete removes a previously persisted network configuration.
+func (d *driver) storeDelete(config *configuration) error {
+	if d.store == nil {
+		logrus.Debugf("ipvlan store not initialized, skipping delete for network %s", config.ID)
+		return nil
+	}
+	if err := d.store.DeleteObjectAtomic(config); err != nil {
+		if err == datastore.ErrKeyModified || err == datastore.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// initStore initializes the driver's persistent store and restores any
+// networks that survived a daemon restart.
+func (d *driver) initStore(option map[string]interface{}) error {
+	if data, ok := option[netlabel.LocalKVClient]; ok {
+		var err error
+		dsc, ok := data.(discoverapi.DatastoreConfigData)
+		if !ok {
+			return types.BadRequestErrorf("incorrect data in datastore configuration: %v", data)
+		}
+		d.store, err = datastore.NewDataStoreFromConfig(dsc)
+		if err != nil {
+			return err
+		}
+	}
+	if d.store == nil {
+		return nil
+	}
+	return d.populateNetworks()
+}
+
+func (d *driver) populateNetworks() error {
+	kvol, err := d.store.List(datastore.Key(ipvlanPrefix), &configuration{})
+	if err != nil && err != datastore.ErrKeyNotFound {
+		return types.InternalErrorf("failed to get ipvlan network configurations from store: %v", err)
+	}
+	if err == datastore.ErrKeyNotFound {
+		return nil
+	}
+	// restore config-only networks first, since ConfigFrom networks
+	// resolve their parent/mode/flag by looking one up at create time
+	var rest []*configuration
+	for _, kvo := range kvol {
+		config := kvo.(*configuration)
+		if config.ConfigOnly {
+			d.addConfigNetwork(config)
+			continue
+		}
+		rest = append(rest, config)
+	}
+	for _, config := range rest {
+		if err = d.createNetwork(config); err != nil {
+			// a consumer that fails to restore never gets a *network, so it
+			// can never reach DeleteNetwork to release its reference --
+			// only count it against the config-only network once it has
+			// actually come back up
+			logrus.Warnf("could not restore ipvlan network %s: %v", config.ID, err)
+			continue
+		}
+		if config.ConfigFrom != "" {
+			d.attachConfigFromConsumer(config)
+		}
+	}
+	return nil
+}
+
+// Key, KeyPrefix, Value, SetValue, Index, SetIndex, Exists, Skip, New, CopyTo
+// implement datastore.KVObject so the configuration can be stored directly.
+
+func (config *configuration) Key() []string {
+	return []string{ipvlanPrefix, config.ID}
+}
+
+func (config *configuration) KeyPrefix() []string {
+	return []string{ipvlanPrefix}
+}
+
+func (config *configuration) Value() []byte {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func (config *configuration) SetValue(value []byte) error {
+	return json.Unmarshal(value, config)
+}
+
+func (config *configuration) Index() uint64 {
+	return config.dbIndex
+}
+
+func (config *configuration) SetIndex(index uint64) {
+	config.dbIndex = index
+	config.dbExists = true
+}
+
+func (config *configuration) Exists() bool {
+	return config.dbExists
+}
+
+func (config *configuration) Skip() bool {
+	return false
+}
+
+func (config *configuration) New() datastore.KVObject {
+	return &configuration{}
+}
+
+func (config *configuration) CopyTo(o datastore.KVObject) error {
+	dstConfig := o.(*configuration)
+	*dstConfig = *config
+	return nil
+}
+
+func (config *configuration) DataScope() string {
+	return datastore.LocalScope
+}